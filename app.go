@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// App wires together the subsystems a notification passes through, and
+// exposes Process as the single entry point both the HTTP POST handler and
+// every registered EventSource feed into - so a notification is handled
+// identically regardless of whether it arrived over HTTP or off a queue.
+type App struct {
+	Registry     *Registry
+	Repo         NotificationRepository
+	Pipeline     *Pipeline
+	RetryWorker  *RetryWorker
+	AttemptStore AttemptStore
+}
+
+// Process routes notification by its NotificationType first: Instant sends
+// it straight away, Batch queues it into the pipeline. Only a notification
+// with neither type set (and so no delivery instructions at all) falls back
+// to persist-only - destinations are not the deciding factor, since some
+// batched categories (e.g. "push") have none and must still reach the
+// pipeline the same way email does.
+func (a *App) Process(ctx context.Context, notification Notification) error {
+	switch notification.NotificationType {
+	case Instant:
+		body := bodyText(notification.Body)
+		for _, dest := range notification.Destinations {
+			if err := a.Registry.Send(ctx, dest, notification.EventName, body, notification.Attributes); err != nil {
+				deliveryErr := NewDeliveryError(notification.EventName, recipientType(dest), dest, "instant send failed, queued for retry", err)
+				a.RetryWorker.Enqueue(notification.ID, dest, notification.EventName, body, deliveryErr)
+			}
+		}
+		return nil
+	case Batch:
+		if err := a.Pipeline.Ingest(ctx, notification); err != nil {
+			return fmt.Errorf("queue notification: %w", err)
+		}
+		return nil
+	default:
+		if len(notification.Destinations) == 0 {
+			if err := a.Repo.Insert(notification); err != nil {
+				return fmt.Errorf("insert notification: %w", err)
+			}
+			return nil
+		}
+		return &ValidationError{msg: fmt.Sprintf("invalid notification type %q", notification.NotificationType)}
+	}
+}
+
+// ValidationError marks a Process failure as the caller's fault (bad input)
+// rather than an internal failure, so the HTTP handler can map it to 400
+// instead of 500.
+type ValidationError struct{ msg string }
+
+func (e *ValidationError) Error() string { return e.msg }
@@ -0,0 +1,32 @@
+package main
+
+import "fmt"
+
+// DeliveryError is an oops-style structured error: every field a caller
+// needs to diagnose a failed send is a named attribute rather than buried in
+// a formatted string, so it can round-trip through JSON (e.g. as the
+// LastError on a DeliveryAttempt) instead of collapsing into a bare message.
+type DeliveryError struct {
+	SourceEvent   string `json:"sourceEvent"`
+	RecipientType string `json:"recipientType"`
+	DeliveryURL   string `json:"deliveryUrl"`
+	Hint          string `json:"hint"`
+	Cause         string `json:"cause"`
+}
+
+// NewDeliveryError wraps cause with the context needed to act on a failed
+// delivery: which event produced it, what kind of destination it was headed
+// to, the exact destination URL, and a human-readable hint.
+func NewDeliveryError(sourceEvent, recipientType, deliveryURL, hint string, cause error) *DeliveryError {
+	return &DeliveryError{
+		SourceEvent:   sourceEvent,
+		RecipientType: recipientType,
+		DeliveryURL:   deliveryURL,
+		Hint:          hint,
+		Cause:         cause.Error(),
+	}
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("delivery to %s (%s) for event %q failed: %s (%s)", e.DeliveryURL, e.RecipientType, e.SourceEvent, e.Cause, e.Hint)
+}
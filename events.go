@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Event is a single message published on an EventBus, e.g.
+// "notification.send.failed".
+type Event struct {
+	Name    string
+	Payload any
+}
+
+// EventBus is a small in-process pub/sub so subsystems (the retry worker
+// today, EventSource implementations later) can announce things happened
+// without importing each other. Subscribers that don't keep up with Publish
+// simply miss events rather than blocking it - this is a best-effort signal,
+// not a durable queue.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[string][]chan Event
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[string][]chan Event)}
+}
+
+// Subscribe returns a channel that receives every future Event published
+// under name. The channel is closed when ctx is cancelled.
+func (b *EventBus) Subscribe(ctx context.Context, name string) <-chan Event {
+	ch := make(chan Event, 16)
+
+	b.mu.Lock()
+	b.subs[name] = append(b.subs[name], ch)
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.subs[name]
+		for i, sub := range subs {
+			if sub == ch {
+				b.subs[name] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Publish fans Event out to every subscriber of event.Name, dropping it for
+// any subscriber whose channel is currently full.
+func (b *EventBus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ch := range b.subs[event.Name] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
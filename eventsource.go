@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PayloadFormat describes how an EventSource should interpret a raw message
+// body before turning it into a Notification, mirroring the GCS object
+// notification spec's payloadFormat values.
+type PayloadFormat string
+
+const (
+	// PayloadFormatJSONAPIV1 means the message body is a JSON-encoded
+	// Notification (the same shape the HTTP POST endpoint accepts).
+	PayloadFormatJSONAPIV1 PayloadFormat = "JSON_API_V1"
+	// PayloadFormatNone means the message carries no body worth decoding;
+	// only its attributes and EventName are used to build a Notification.
+	PayloadFormatNone PayloadFormat = "NONE"
+)
+
+// TopicMapping binds one external queue/topic to an internal event name and
+// tells the EventSource how to read messages published on it.
+type TopicMapping struct {
+	Topic            string
+	EventName        string
+	PayloadFormat    PayloadFormat
+	ObjectNamePrefix string
+	CustomAttributes map[string]string
+}
+
+// matchesPrefix reports whether objectName should be translated into a
+// Notification under this mapping. An empty ObjectNamePrefix matches
+// everything.
+func (m TopicMapping) matchesPrefix(objectName string) bool {
+	return m.ObjectNamePrefix == "" || strings.HasPrefix(objectName, m.ObjectNamePrefix)
+}
+
+// Sink is how an EventSource hands a translated message off to the rest of
+// the service - the same entry point the HTTP POST handler uses, so a
+// notification is processed identically regardless of where it came from.
+type Sink func(ctx context.Context, notification Notification) error
+
+// EventSource drives Sink from an external queue/pub-sub system instead of
+// an HTTP request body.
+type EventSource interface {
+	// Run consumes messages until ctx is cancelled or a fatal error occurs.
+	Run(ctx context.Context, sink Sink) error
+}
+
+// SourceRegistry is where EventSources are registered by the event name they
+// produce, mirroring Registry's scheme-keyed dispatch for notifiers.
+type SourceRegistry struct {
+	sources map[string]EventSource
+}
+
+func NewSourceRegistry() *SourceRegistry {
+	return &SourceRegistry{sources: make(map[string]EventSource)}
+}
+
+// Register associates an event name with the EventSource that produces it.
+func (r *SourceRegistry) Register(eventName string, source EventSource) {
+	r.sources[eventName] = source
+}
+
+// RunAll starts every registered EventSource as its own goroutine, feeding
+// sink, and blocks until ctx is cancelled.
+func (r *SourceRegistry) RunAll(ctx context.Context, sink Sink) {
+	for eventName, source := range r.sources {
+		go func(eventName string, source EventSource) {
+			if err := source.Run(ctx, sink); err != nil && ctx.Err() == nil {
+				fmt.Printf("event source for %q stopped: %v\n", eventName, err)
+			}
+		}(eventName, source)
+	}
+	<-ctx.Done()
+}
+
+// RawMessage is one unit of work off an external queue/subscription, already
+// split into the pieces notificationFromMessage needs - object name (SQS/GCS
+// key, NATS subject token, ...), any message-level attributes, and the raw
+// body. Each EventSource's real client wiring is responsible for producing
+// these; consumeMessages is what turns them into Notifications.
+type RawMessage struct {
+	ObjectName string
+	Attributes map[string]string
+	Body       []byte
+}
+
+// consumeMessages drains messages until ctx is cancelled or the channel is
+// closed, translating each one via mapping/notificationFromMessage and
+// handing the result to sink. A translation or sink error is reported but
+// does not stop the loop, mirroring how a real queue consumer would skip a
+// bad message rather than wedge the whole subscription.
+func consumeMessages(ctx context.Context, messages <-chan RawMessage, mapping TopicMapping, sink Sink) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			if !mapping.matchesPrefix(msg.ObjectName) {
+				continue
+			}
+			notification, err := notificationFromMessage(mapping, msg.ObjectName, msg.Attributes, msg.Body)
+			if err != nil {
+				fmt.Printf("eventsource: %v\n", err)
+				continue
+			}
+			if err := sink(ctx, notification); err != nil {
+				fmt.Printf("eventsource: sink for %q: %v\n", mapping.Topic, err)
+			}
+		}
+	}
+}
+
+// SQSEventSource consumes an AWS SQS queue per TopicMapping. Messages is the
+// translated feed of the real long-polling client, which has not been
+// implemented yet; a nil Messages makes Run behave as a pure stub.
+type SQSEventSource struct {
+	QueueURL string
+	Mapping  TopicMapping
+	Messages <-chan RawMessage
+}
+
+func (s SQSEventSource) Run(ctx context.Context, sink Sink) error {
+	// TODO: replace Messages with the real long-polling s.QueueURL client;
+	// until then this just drains whatever is fed in (e.g. in tests).
+	if s.Messages == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return consumeMessages(ctx, s.Messages, s.Mapping, sink)
+}
+
+// PubSubEventSource consumes a GCP Pub/Sub subscription per TopicMapping.
+// Messages is the translated feed of the real subscription client, which has
+// not been implemented yet; a nil Messages makes Run behave as a pure stub.
+type PubSubEventSource struct {
+	Subscription string
+	Mapping      TopicMapping
+	Messages     <-chan RawMessage
+}
+
+func (p PubSubEventSource) Run(ctx context.Context, sink Sink) error {
+	// TODO: replace Messages with the real p.Subscription client; until
+	// then this just drains whatever is fed in (e.g. in tests).
+	if p.Messages == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return consumeMessages(ctx, p.Messages, p.Mapping, sink)
+}
+
+// NATSEventSource consumes a NATS subject per TopicMapping. Messages is the
+// translated feed of the real subscription client, which has not been
+// implemented yet; a nil Messages makes Run behave as a pure stub.
+type NATSEventSource struct {
+	Subject  string
+	Mapping  TopicMapping
+	Messages <-chan RawMessage
+}
+
+func (n NATSEventSource) Run(ctx context.Context, sink Sink) error {
+	// TODO: replace Messages with the real n.Subject client; until then
+	// this just drains whatever is fed in (e.g. in tests).
+	if n.Messages == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return consumeMessages(ctx, n.Messages, n.Mapping, sink)
+}
+
+// notificationFromMessage builds a Notification from a raw external message
+// according to mapping, merging mapping.CustomAttributes with any
+// attributes carried by the message itself.
+func notificationFromMessage(mapping TopicMapping, objectName string, messageAttributes map[string]string, rawBody []byte) (Notification, error) {
+	attrs := make(map[string]string, len(mapping.CustomAttributes)+len(messageAttributes))
+	for k, v := range mapping.CustomAttributes {
+		attrs[k] = v
+	}
+	for k, v := range messageAttributes {
+		attrs[k] = v
+	}
+
+	switch mapping.PayloadFormat {
+	case PayloadFormatJSONAPIV1:
+		var notification Notification
+		if err := notification.UnmarshalJSON(rawBody); err != nil {
+			return Notification{}, fmt.Errorf("eventsource: decode %s message: %w", mapping.Topic, err)
+		}
+		notification.EventName = mapping.EventName
+		notification.Attributes = attrs
+		return notification, nil
+	case PayloadFormatNone:
+		return Notification{EventName: mapping.EventName, Attributes: attrs}, nil
+	default:
+		return Notification{}, fmt.Errorf("eventsource: unsupported payload format %q", mapping.PayloadFormat)
+	}
+}
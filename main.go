@@ -1,37 +1,100 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"time"
 )
 
-type DeliveryRoute string
 type NotificationType string
 
 const (
-	Email  DeliveryRoute = "email"
-	System DeliveryRoute = "system"
-
 	Instant NotificationType = "instant"
 	Batch   NotificationType = "batch"
 )
 
 type Notification struct {
-	Date             string           `json:"date"`
-	EventName        string           `json:"eventName"`
-	DeliveryRoute    DeliveryRoute    `json:"deliveryRoute"`
-	NotificationType NotificationType `json:"notificationType"`
-	Metadata         json.RawMessage  `json:"metadata"`
+	ID               string
+	Date             string
+	EventName        string
+	Destinations     []string
+	NotificationType NotificationType
+	Category         string
+	Body             NotificationBody
+	// Attributes carries extra key/value context forwarded in from an
+	// EventSource (e.g. a queue message's custom attributes); it is never
+	// set by the HTTP POST endpoint.
+	Attributes map[string]string
 }
 
 // I believe it would be better to use the metadata as just the directory but the assignment said that the body of was a part of the metadata
 
+// Destinations carries zero or more DSN-style URLs (see notifier.go). A
+// notification with no destinations is treated as an internal/system
+// notification and is only persisted, not delivered.
+
+// notificationWire is the JSON shape of a Notification on the wire: Body is
+// kept as raw metadata until Category tells UnmarshalJSON which
+// NotificationBody to decode it into (see notification_body.go).
+type notificationWire struct {
+	ID               string            `json:"id"`
+	Date             string            `json:"date"`
+	EventName        string            `json:"eventName"`
+	Destinations     []string          `json:"destinations"`
+	NotificationType NotificationType  `json:"notificationType"`
+	Category         string            `json:"category"`
+	Metadata         json.RawMessage   `json:"metadata"`
+	Attributes       map[string]string `json:"attributes,omitempty"`
+}
+
+func (n Notification) MarshalJSON() ([]byte, error) {
+	metadata, err := json.Marshal(n.Body)
+	if err != nil {
+		return nil, fmt.Errorf("notification: marshal body: %w", err)
+	}
+	return json.Marshal(notificationWire{
+		ID:               n.ID,
+		Date:             n.Date,
+		EventName:        n.EventName,
+		Destinations:     n.Destinations,
+		NotificationType: n.NotificationType,
+		Category:         n.Category,
+		Metadata:         metadata,
+		Attributes:       n.Attributes,
+	})
+}
+
+func (n *Notification) UnmarshalJSON(data []byte) error {
+	var wire notificationWire
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	body, err := unmarshalBody(wire.Category, wire.Metadata)
+	if err != nil {
+		return err
+	}
+
+	n.ID = wire.ID
+	n.Date = wire.Date
+	n.EventName = wire.EventName
+	n.Destinations = wire.Destinations
+	n.NotificationType = wire.NotificationType
+	n.Category = wire.Category
+	n.Body = body
+	n.Attributes = wire.Attributes
+	return nil
+}
+
 type EmailMetadata struct {
-	EmailAddress string `json:"emailAddress"`
-	EmailBody    string `json:"emailBody"`
+	EmailBody string `json:"emailBody"`
 }
 
 type SystemMetadata struct {
@@ -42,24 +105,63 @@ type SystemMetadata struct {
 func main() {
 	r := gin.Default()
 
-	// Imagine this map are several SQS queues by topic, otherwise there isn't persistence
-	var notificationsMap = make(map[string][]EmailMetadata)
+	registry := NewRegistry()
+	RegisterDefaults(registry)
 
-	// Email service Dependency Injection
-	var emailService EmailService
 	var notificationRepo NotificationRepository
-	var batchAmount int
-
-	switch os.Getenv("EMAIL_PROTOCOL") {
-	case "SMTP":
-		emailService = SMTPService{}
-	case "OTHER":
-		emailService = OtherProtocolService{}
-	default:
-		log.Fatal("Invalid email protocol")
+	var pushService PushService
+	var tokenRepo TokenRepository
+
+	actors := DefaultActors()
+	actors["webhook"] = WebhookActor(registry)
+	actors["email"] = EmailActor(registry)
+	actors["push"] = PushActor(pushService, tokenRepo)
+
+	pipelineConfig, err := LoadPipelineConfig(os.Getenv("BATCHING_GROUPS_CONFIG"))
+	if err != nil {
+		panic(err)
+	}
+
+	defaultPolicy, err := pipelineConfig.DefaultPolicy.toTriggerPolicy()
+	if err != nil {
+		panic(err)
 	}
+	if defaultPolicy == (TriggerPolicy{}) {
+		// No defaultPolicy configured: fall back to the legacy BATCH_AMOUNT
+		// count trigger so existing deployments keep working.
+		defaultPolicy = TriggerPolicy{Count: batchCountFromEnv()}
+	}
+
+	eventPolicies := make(map[string]TriggerPolicy, len(pipelineConfig.EventPolicies))
+	for eventName, policyConfig := range pipelineConfig.EventPolicies {
+		eventPolicy, err := policyConfig.toTriggerPolicy()
+		if err != nil {
+			panic(err)
+		}
+		eventPolicies[eventName] = eventPolicy
+	}
+
+	events := NewEventBus()
+	attemptStore := NewInMemoryAttemptStore()
+	retryWorker := NewRetryWorker(attemptStore, registry, events, 5, time.Second, time.Minute)
+	go retryWorker.Run(context.Background(), time.Second)
 
-	// Same logic as above for the notificationRepo and batchAmount
+	pipeline := NewPipeline(NewInMemoryBatchStore(), defaultPolicy, eventPolicies, pipelineConfig.Groups, actors, retryWorker)
+	go pipeline.Run(context.Background(), time.Second)
+
+	app := &App{
+		Registry:     registry,
+		Repo:         notificationRepo,
+		Pipeline:     pipeline,
+		RetryWorker:  retryWorker,
+		AttemptStore: attemptStore,
+	}
+
+	sources := NewSourceRegistry()
+	// Register EventSources here, e.g. sources.Register("order.placed", SQSEventSource{...})
+	go sources.RunAll(context.Background(), app.Process)
+
+	// Same logic as above for the notificationRepo, pushService and tokenRepo
 
 	// Endpoints
 
@@ -70,50 +172,13 @@ func main() {
 			return
 		}
 
-		switch notification.DeliveryRoute {
-		case Email:
-			var emailMetadata EmailMetadata
-			if err := json.Unmarshal(notification.Metadata, &emailMetadata); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email metadata"})
-				return
-			}
-
-			switch notification.NotificationType {
-			case Instant:
-				if err := emailService.SendEmail(emailMetadata.EmailAddress, emailMetadata.EmailBody); err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send email"})
-					return
-				}
-			case Batch:
-				// Add the notification to the queue accordingly
-				notificationsMap[notification.EventName] = append(notificationsMap[notification.EventName], emailMetadata)
-
-				// Check if there are batchAmount notifications for this event
-				if len(notificationsMap[notification.EventName]) == batchAmount {
-					// Join all the email bodies together
-					var emailBody string
-					for _, email := range notificationsMap[notification.EventName] {
-						emailBody += email.EmailBody + "\n"
-					}
-
-					// Send the email
-					if err := emailService.SendEmail(notificationsMap[notification.EventName][0].EmailAddress, emailBody); err != nil {
-						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send email"})
-						return
-					}
-
-					// Clear the notifications for this event
-					notificationsMap[notification.EventName] = []EmailMetadata{}
-				}
-			}
-		case System:
-			// Handle system notification type here
-			if err := notificationRepo.Insert(notification); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to insert notification"})
+		if err := app.Process(c, notification); err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-		default:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification type"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
@@ -149,27 +214,39 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
 	})
 
-	r.Run()
-}
-
-// Email Service
+	r.GET("/notifications/:id/attempts", func(c *gin.Context) {
+		id := c.Param("id")
+		attempts, err := attemptStore.ListForNotification(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get delivery attempts"})
+			return
+		}
+		c.JSON(http.StatusOK, attempts)
+	})
 
-type EmailService interface {
-	SendEmail(address string, body string) error
+	r.Run()
 }
 
-type SMTPService struct{}
-
-func (s SMTPService) SendEmail(address string, body string) error {
-	// Implement SMTP email sending here
-	return nil
+// recipientType extracts the scheme (e.g. "smtp", "slack") from a
+// destination URL for use as DeliveryError.RecipientType. An unparsable
+// destination reports as "unknown" rather than failing the caller.
+func recipientType(destination string) string {
+	dest, err := url.Parse(destination)
+	if err != nil {
+		return "unknown"
+	}
+	return dest.Scheme
 }
 
-type OtherProtocolService struct{}
-
-func (o OtherProtocolService) SendEmail(address string, body string) error {
-	// Implement other protocol email sending here
-	return nil
+// batchCountFromEnv reads the count trigger for the default batching policy
+// from BATCH_AMOUNT. A missing or invalid value disables the count trigger,
+// leaving maxAge/sizeBytes (if configured on a group) as the only triggers.
+func batchCountFromEnv() int {
+	amount, err := strconv.Atoi(os.Getenv("BATCH_AMOUNT"))
+	if err != nil {
+		return 0
+	}
+	return amount
 }
 
 // Notification Repository
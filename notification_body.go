@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// NotificationBody is the payload shape carried by a Notification. Each
+// concrete implementation reports the Category it must be registered under,
+// so Notification's JSON (un)marshalling can dispatch on the wire "category"
+// field instead of every handler doing its own json.Unmarshal into a
+// hardcoded struct.
+type NotificationBody interface {
+	Category() string
+}
+
+// bodyConstructors maps a Category to a constructor for its NotificationBody.
+// RegisterCategory is how downstream code (and this package's init) adds to
+// it; there is no handler-level switch to extend when a new category is
+// added.
+var bodyConstructors = map[string]func() NotificationBody{}
+
+// RegisterCategory associates a category name with a constructor for the
+// NotificationBody it should unmarshal into. Registering the same category
+// twice overwrites the earlier constructor.
+func RegisterCategory(category string, constructor func() NotificationBody) {
+	bodyConstructors[category] = constructor
+}
+
+func init() {
+	RegisterCategory("message", func() NotificationBody { return &MessageNotificationBody{} })
+	RegisterCategory("transaction", func() NotificationBody { return &TransactionNotificationBody{} })
+	RegisterCategory("email", func() NotificationBody { return &EmailNotificationBody{} })
+	RegisterCategory("system-alert", func() NotificationBody { return &SystemNotificationBody{} })
+}
+
+// MessageNotificationBody is the body for a "message"/"mention" style
+// notification: someone said something in a chat.
+type MessageNotificationBody struct {
+	Message string `json:"message"`
+	Contact string `json:"contact"`
+	Chat    string `json:"chat"`
+}
+
+func (m *MessageNotificationBody) Category() string { return "message" }
+
+// TransactionNotificationBody is the body for a "transaction" notification:
+// money moved.
+type TransactionNotificationBody struct {
+	TransactionID string  `json:"transactionId"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+}
+
+func (t *TransactionNotificationBody) Category() string { return "transaction" }
+
+// EmailNotificationBody is the "email" category body, replacing the old
+// handler-level EmailMetadata unmarshal.
+type EmailNotificationBody struct {
+	EmailMetadata
+}
+
+func (e *EmailNotificationBody) Category() string { return "email" }
+
+// SystemNotificationBody is the "system-alert" category body, replacing the
+// old handler-level SystemMetadata unmarshal.
+type SystemNotificationBody struct {
+	SystemMetadata
+}
+
+func (s *SystemNotificationBody) Category() string { return "system-alert" }
+
+// bodyText renders body as the plain-text content a Notifier sends. The
+// email category has a natural text form (its EmailBody); everything else
+// falls back to its JSON representation.
+func bodyText(body NotificationBody) string {
+	if email, ok := body.(*EmailNotificationBody); ok {
+		return email.EmailBody
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// unmarshalBody looks up category's constructor and decodes raw into the
+// resulting NotificationBody. Decoding rejects any field raw carries that
+// the category's shape doesn't declare, so a genuine bodyType mismatch
+// (e.g. transaction metadata posted under the email category) fails loudly
+// instead of silently zero-valuing the fields it didn't recognize.
+func unmarshalBody(category string, raw json.RawMessage) (NotificationBody, error) {
+	constructor, ok := bodyConstructors[category]
+	if !ok {
+		return nil, fmt.Errorf("notification: unknown category %q", category)
+	}
+
+	body := constructor()
+	if len(raw) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(body); err != nil {
+			return nil, fmt.Errorf("notification: metadata does not match category %q: %w", category, err)
+		}
+	}
+	if body.Category() != category {
+		return nil, fmt.Errorf("notification: bodyType mismatch: declared category %q, body is %q", category, body.Category())
+	}
+	return body, nil
+}
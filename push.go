@@ -0,0 +1,115 @@
+package main
+
+import "context"
+
+// PushResult reports the per-registration-id outcome of a push send,
+// mirroring how FCM/GCM batch responses surface per-token failures instead
+// of a single pass/fail for the whole call.
+type PushResult struct {
+	RegistrationID string
+	Error          string
+	// CanonicalID is set when the provider returns a new canonical id for
+	// RegistrationID; callers should start using it instead of retrying the
+	// old one.
+	CanonicalID string
+	// InvalidToken is set when the provider reports RegistrationID as
+	// permanently invalid (e.g. NotRegistered/Unregistered); callers should
+	// remove it rather than retry.
+	InvalidToken bool
+}
+
+// PushDisplay is the user-visible half of a push payload - what FCM calls
+// "notification" and APNs calls "alert" - kept separate from PushMetadata's
+// Data so callers can tell the display payload from the data payload.
+type PushDisplay struct {
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	Icon        string `json:"icon"`
+	Sound       string `json:"sound"`
+	ClickAction string `json:"clickAction"`
+}
+
+// PushMetadata is the "push" NotificationBody: an FCM/GCM-style fan-out of
+// one payload to a set of device registration ids.
+type PushMetadata struct {
+	RegistrationIDs []string       `json:"registrationIds"`
+	CollapseKey     string         `json:"collapseKey"`
+	TimeToLive      int            `json:"timeToLive"`
+	Data            map[string]any `json:"data"`
+	Display         PushDisplay    `json:"display"`
+}
+
+func (p *PushMetadata) Category() string { return "push" }
+
+func init() {
+	RegisterCategory("push", func() NotificationBody { return &PushMetadata{} })
+}
+
+// PushService sends a push payload to a set of device registration ids and
+// reports the outcome per id.
+type PushService interface {
+	Send(ctx context.Context, metadata PushMetadata) ([]PushResult, error)
+}
+
+type FCMService struct{}
+
+func (f FCMService) Send(ctx context.Context, metadata PushMetadata) ([]PushResult, error) {
+	// Implement the FCM HTTP v1 batch send here, mapping its per-message
+	// results (including canonical id remapping) onto PushResult.
+	return nil, nil
+}
+
+type APNsService struct{}
+
+func (a APNsService) Send(ctx context.Context, metadata PushMetadata) ([]PushResult, error) {
+	// Implement the APNs HTTP/2 provider API send here.
+	return nil, nil
+}
+
+// TokenRepository lets push delivery clean up stale device registration ids
+// discovered from provider responses.
+type TokenRepository interface {
+	RemoveToken(registrationID string) error
+	ReplaceToken(oldID, newID string) error
+}
+
+type TokenRepositoryImpl struct{}
+
+func (t TokenRepositoryImpl) RemoveToken(registrationID string) error {
+	// Implement removing the stale token from storage here.
+	return nil
+}
+
+func (t TokenRepositoryImpl) ReplaceToken(oldID, newID string) error {
+	// Implement remapping the token to its canonical id here.
+	return nil
+}
+
+// PushActor sends every "push" notification in a batch through service, the
+// same way EmailActor does for "email", and cleans up any device token
+// service reports as stale or superseded via repo.
+func PushActor(service PushService, repo TokenRepository) Actor {
+	return ActorFunc(func(ctx context.Context, batch PendingBatch) error {
+		for _, n := range batch.Notifications {
+			push, ok := n.Body.(*PushMetadata)
+			if !ok {
+				continue
+			}
+
+			results, err := service.Send(ctx, *push)
+			if err != nil {
+				return err
+			}
+
+			for _, result := range results {
+				switch {
+				case result.InvalidToken:
+					_ = repo.RemoveToken(result.RegistrationID)
+				case result.CanonicalID != "":
+					_ = repo.ReplaceToken(result.RegistrationID, result.CanonicalID)
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DeliveryAttempt records one attempt (successful or not) to deliver a
+// notification to a single destination, so GET /notifications/:id/attempts
+// can return actionable diagnostics instead of the notification simply
+// vanishing on failure.
+type DeliveryAttempt struct {
+	NotificationID string         `json:"notificationId"`
+	Destination    string         `json:"destination"`
+	Subject        string         `json:"subject"`
+	Body           string         `json:"body"`
+	AttemptCount   int            `json:"attemptCount"`
+	LastError      *DeliveryError `json:"lastError,omitempty"`
+	NextRetryAt    time.Time      `json:"nextRetryAt"`
+}
+
+// AttemptStore persists DeliveryAttempts so the retry worker can resume
+// after a restart and so attempts stay queryable per notification.
+type AttemptStore interface {
+	// Record upserts an attempt, keyed by (NotificationID, Destination).
+	Record(attempt DeliveryAttempt) error
+	// ListForNotification returns every attempt recorded for a notification.
+	ListForNotification(notificationID string) ([]DeliveryAttempt, error)
+	// Due returns every attempt whose NextRetryAt has passed.
+	Due(now time.Time) ([]DeliveryAttempt, error)
+	// Remove deletes an attempt once it has succeeded.
+	Remove(notificationID, destination string) error
+}
+
+// InMemoryAttemptStore is the zero-dependency AttemptStore.
+type InMemoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*DeliveryAttempt
+}
+
+func NewInMemoryAttemptStore() *InMemoryAttemptStore {
+	return &InMemoryAttemptStore{attempts: make(map[string]*DeliveryAttempt)}
+}
+
+func attemptKey(notificationID, destination string) string {
+	return notificationID + "\x00" + destination
+}
+
+func (s *InMemoryAttemptStore) Record(attempt DeliveryAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := attempt
+	s.attempts[attemptKey(attempt.NotificationID, attempt.Destination)] = &stored
+	return nil
+}
+
+func (s *InMemoryAttemptStore) ListForNotification(notificationID string) ([]DeliveryAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var attempts []DeliveryAttempt
+	for _, attempt := range s.attempts {
+		if attempt.NotificationID == notificationID {
+			attempts = append(attempts, *attempt)
+		}
+	}
+	return attempts, nil
+}
+
+func (s *InMemoryAttemptStore) Due(now time.Time) ([]DeliveryAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []DeliveryAttempt
+	for _, attempt := range s.attempts {
+		if !attempt.NextRetryAt.After(now) {
+			due = append(due, *attempt)
+		}
+	}
+	return due, nil
+}
+
+func (s *InMemoryAttemptStore) Remove(notificationID, destination string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, attemptKey(notificationID, destination))
+	return nil
+}
+
+// RetryWorker retries failed sends through a Registry with exponential
+// backoff and jitter, up to MaxAttempts, persisting progress through an
+// AttemptStore so a restart resumes rather than losing the notification.
+type RetryWorker struct {
+	Store       AttemptStore
+	Registry    *Registry
+	Events      *EventBus
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func NewRetryWorker(store AttemptStore, registry *Registry, events *EventBus, maxAttempts int, baseDelay, maxDelay time.Duration) *RetryWorker {
+	return &RetryWorker{
+		Store:       store,
+		Registry:    registry,
+		Events:      events,
+		MaxAttempts: maxAttempts,
+		BaseDelay:   baseDelay,
+		MaxDelay:    maxDelay,
+	}
+}
+
+// Enqueue records a failed first send so the background worker picks it up
+// on its next tick.
+func (w *RetryWorker) Enqueue(notificationID, destination, subject, body string, deliveryErr *DeliveryError) {
+	_ = w.Store.Record(DeliveryAttempt{
+		NotificationID: notificationID,
+		Destination:    destination,
+		Subject:        subject,
+		Body:           body,
+		AttemptCount:   1,
+		LastError:      deliveryErr,
+		NextRetryAt:    time.Now().Add(w.backoff(1)),
+	})
+	w.Events.Publish(Event{Name: "notification.send.failed", Payload: deliveryErr})
+}
+
+// backoff computes an exponential delay with jitter for the given attempt
+// number (1-indexed), capped at MaxDelay.
+func (w *RetryWorker) backoff(attempt int) time.Duration {
+	delay := w.BaseDelay << uint(attempt-1)
+	if delay > w.MaxDelay || delay <= 0 {
+		delay = w.MaxDelay
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay/2 + jitter
+}
+
+// Run ticks the worker on the given interval, resending every due attempt
+// until ctx is cancelled.
+func (w *RetryWorker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.tick(ctx)
+		}
+	}
+}
+
+func (w *RetryWorker) tick(ctx context.Context) {
+	due, err := w.Store.Due(time.Now())
+	if err != nil {
+		return
+	}
+
+	for _, attempt := range due {
+		err := w.Registry.Send(ctx, attempt.Destination, attempt.Subject, attempt.Body, nil)
+		if err == nil {
+			_ = w.Store.Remove(attempt.NotificationID, attempt.Destination)
+			continue
+		}
+
+		attempt.AttemptCount++
+		attempt.LastError = NewDeliveryError(attempt.LastError.SourceEvent, attempt.LastError.RecipientType, attempt.Destination, "retry failed", err)
+
+		if attempt.AttemptCount > w.MaxAttempts {
+			_ = w.Store.Remove(attempt.NotificationID, attempt.Destination)
+			w.Events.Publish(Event{Name: "notification.send.failed", Payload: attempt.LastError})
+			continue
+		}
+
+		attempt.NextRetryAt = time.Now().Add(w.backoff(attempt.AttemptCount))
+		_ = w.Store.Record(attempt)
+		w.Events.Publish(Event{Name: "notification.send.failed", Payload: attempt.LastError})
+	}
+}